@@ -0,0 +1,297 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// ListOptions filters the result of CLI.List.
+type ListOptions struct {
+	Context string
+	Style   string
+}
+
+// List returns the stored annotations, optionally filtered by context name
+// and/or style.
+func (c *CLI) List(opts ListOptions) ([]AnnotationEntry, error) {
+	env, err := c.loadEnvelope(c.annotationsFile)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Context == "" && opts.Style == "" {
+		return env.Annotations, nil
+	}
+
+	filtered := make([]AnnotationEntry, 0, len(env.Annotations))
+	for _, e := range env.Annotations {
+		if opts.Context != "" && e.ContextName != opts.Context {
+			continue
+		}
+		if opts.Style != "" && e.Style != opts.Style {
+			continue
+		}
+		filtered = append(filtered, e)
+	}
+	return filtered, nil
+}
+
+// Get returns the stored annotation for contextName, reporting whether one
+// was found.
+func (c *CLI) Get(contextName string) (AnnotationEntry, bool, error) {
+	env, err := c.loadEnvelope(c.annotationsFile)
+	if err != nil {
+		return AnnotationEntry{}, false, err
+	}
+	for _, e := range env.Annotations {
+		if e.ContextName == contextName {
+			return e, true, nil
+		}
+	}
+	return AnnotationEntry{}, false, nil
+}
+
+// Delete removes the stored annotation for contextName, reporting whether
+// an entry was actually removed.
+func (c *CLI) Delete(contextName string) (bool, error) {
+	lock := newFileLock(c.annotationsFile)
+	if err := lock.Acquire(c.lockTimeout); err != nil {
+		return false, err
+	}
+	defer lock.Release()
+
+	env, err := c.loadEnvelope(c.annotationsFile)
+	if err != nil {
+		return false, err
+	}
+
+	idx := -1
+	for i := range env.Annotations {
+		if env.Annotations[i].ContextName == contextName {
+			idx = i
+			break
+		}
+	}
+	if idx == -1 {
+		return false, nil
+	}
+
+	env.Annotations = append(env.Annotations[:idx], env.Annotations[idx+1:]...)
+	if err := c.saveEnvelope(c.annotationsFile, env); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Clear removes every stored annotation, leaving the envelope's
+// planExecutionId untouched.
+func (c *CLI) Clear() error {
+	lock := newFileLock(c.annotationsFile)
+	if err := lock.Acquire(c.lockTimeout); err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	env, err := c.loadEnvelope(c.annotationsFile)
+	if err != nil {
+		return err
+	}
+	env.Annotations = []AnnotationEntry{}
+	return c.saveEnvelope(c.annotationsFile, env)
+}
+
+// Prune removes annotations whose Timestamp is older than olderThan and
+// reports how many were removed. Entries with an unparseable Timestamp are
+// kept rather than guessed at.
+func (c *CLI) Prune(olderThan time.Duration) (int, error) {
+	lock := newFileLock(c.annotationsFile)
+	if err := lock.Acquire(c.lockTimeout); err != nil {
+		return 0, err
+	}
+	defer lock.Release()
+
+	env, err := c.loadEnvelope(c.annotationsFile)
+	if err != nil {
+		return 0, err
+	}
+
+	cutoff := time.Now().Add(-olderThan)
+	kept := env.Annotations[:0]
+	removed := 0
+	for _, e := range env.Annotations {
+		if ts, err := time.Parse(time.RFC3339, e.Timestamp); err == nil && ts.Before(cutoff) {
+			removed++
+			continue
+		}
+		kept = append(kept, e)
+	}
+	env.Annotations = kept
+
+	if removed == 0 {
+		return 0, nil
+	}
+	if err := c.saveEnvelope(c.annotationsFile, env); err != nil {
+		return 0, err
+	}
+	return removed, nil
+}
+
+func runList(args []string) {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	context := fs.String("context", "", "Filter by context name")
+	style := fs.String("style", "", "Filter by style (info|success|warning|error)")
+	asJSON := fs.Bool("json", false, "Print full JSON entries instead of a summary table")
+	logLevel := fs.String("log-level", "info", "Log level for diagnostics (debug|info|warn|error)")
+
+	logger := newLogger("info")
+	if err := fs.Parse(args); err != nil {
+		logger.Warn("failed to parse flags", "error", err)
+		os.Exit(0)
+	}
+	logger = newLogger(*logLevel)
+
+	cli := NewCLI()
+	entries, err := cli.List(ListOptions{Context: *context, Style: *style})
+	if err != nil {
+		logger.Warn(err.Error())
+		os.Exit(0)
+	}
+
+	if *asJSON {
+		data, _ := json.MarshalIndent(entries, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+
+	for _, e := range entries {
+		fmt.Printf("%s\t%s\t%s\t%s\n", e.ContextName, e.Style, e.Timestamp, e.Mode)
+	}
+}
+
+func runGet(args []string) {
+	fs := flag.NewFlagSet("get", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	context := fs.String("context", "", "Context of the step to fetch - required")
+	logLevel := fs.String("log-level", "info", "Log level for diagnostics (debug|info|warn|error)")
+
+	logger := newLogger("info")
+	if err := fs.Parse(args); err != nil {
+		logger.Warn("failed to parse flags", "error", err)
+		os.Exit(0)
+	}
+	logger = newLogger(*logLevel)
+
+	if *context == "" {
+		logger.Warn("--context is required")
+		os.Exit(0)
+	}
+
+	cli := NewCLI()
+	entry, found, err := cli.Get(*context)
+	if err != nil {
+		logger.Warn(err.Error())
+		os.Exit(0)
+	}
+	if !found {
+		logger.Warn("no annotation found", "context", *context)
+		os.Exit(0)
+	}
+
+	data, _ := json.MarshalIndent(entry, "", "  ")
+	fmt.Println(string(data))
+}
+
+func runDelete(args []string) {
+	fs := flag.NewFlagSet("delete", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	context := fs.String("context", "", "Context of the step to delete - required")
+	lockTimeout := addLockTimeoutFlag(fs)
+	logLevel := fs.String("log-level", "info", "Log level for diagnostics (debug|info|warn|error)")
+
+	logger := newLogger("info")
+	if err := fs.Parse(args); err != nil {
+		logger.Warn("failed to parse flags", "error", err)
+		os.Exit(0)
+	}
+	logger = newLogger(*logLevel)
+
+	if *context == "" {
+		logger.Warn("--context is required")
+		os.Exit(0)
+	}
+
+	cli := NewCLI()
+	applyLockTimeout(cli, *lockTimeout)
+	removed, err := cli.Delete(*context)
+	if err != nil {
+		logger.Warn(err.Error())
+		os.Exit(0)
+	}
+	if !removed {
+		logger.Warn("no annotation found", "context", *context)
+		os.Exit(0)
+	}
+	fmt.Printf("deleted annotation for context '%s'\n", *context)
+}
+
+func runClear(args []string) {
+	fs := flag.NewFlagSet("clear", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	all := fs.Bool("all", false, "Confirm removing every stored annotation - required")
+	lockTimeout := addLockTimeoutFlag(fs)
+	logLevel := fs.String("log-level", "info", "Log level for diagnostics (debug|info|warn|error)")
+
+	logger := newLogger("info")
+	if err := fs.Parse(args); err != nil {
+		logger.Warn("failed to parse flags", "error", err)
+		os.Exit(0)
+	}
+	logger = newLogger(*logLevel)
+
+	if !*all {
+		logger.Warn("--all is required to confirm clearing all annotations")
+		os.Exit(0)
+	}
+
+	cli := NewCLI()
+	applyLockTimeout(cli, *lockTimeout)
+	if err := cli.Clear(); err != nil {
+		logger.Warn(err.Error())
+		os.Exit(0)
+	}
+	fmt.Println("cleared all annotations")
+}
+
+func runPrune(args []string) {
+	fs := flag.NewFlagSet("prune", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	olderThan := fs.Duration("older-than", 0, "Remove annotations older than this duration, e.g. 24h - required")
+	lockTimeout := addLockTimeoutFlag(fs)
+	logLevel := fs.String("log-level", "info", "Log level for diagnostics (debug|info|warn|error)")
+
+	logger := newLogger("info")
+	if err := fs.Parse(args); err != nil {
+		logger.Warn("failed to parse flags", "error", err)
+		os.Exit(0)
+	}
+	logger = newLogger(*logLevel)
+
+	if *olderThan <= 0 {
+		logger.Warn("--older-than is required, e.g. --older-than=24h")
+		os.Exit(0)
+	}
+
+	cli := NewCLI()
+	applyLockTimeout(cli, *lockTimeout)
+	removed, err := cli.Prune(*olderThan)
+	if err != nil {
+		logger.Warn(err.Error())
+		os.Exit(0)
+	}
+	fmt.Printf("pruned %d annotation(s) older than %s\n", removed, *olderThan)
+}
@@ -0,0 +1,45 @@
+package main
+
+import "sort"
+
+// envelopeDiff summarizes how mergeEntry would change an envelope's
+// annotations, by context name, without actually saving anything.
+type envelopeDiff struct {
+	Added   []string `json:"added"`
+	Updated []string `json:"updated"`
+	Deleted []string `json:"deleted"`
+}
+
+// diffEnvelope merges entry into a copy of env and reports which contexts
+// would be added, updated, or (soft-)deleted. It never mutates env.
+func diffEnvelope(env AnnotationsEnvelope, entry AnnotationEntry) (AnnotationsEnvelope, envelopeDiff) {
+	before := map[string]AnnotationEntry{}
+	for _, e := range env.Annotations {
+		before[e.ContextName] = e
+	}
+
+	after := AnnotationsEnvelope{
+		SchemaVersion:   env.SchemaVersion,
+		PlanExecutionID: env.PlanExecutionID,
+		Annotations:     append([]AnnotationEntry(nil), env.Annotations...),
+	}
+	mergeEntry(&after, entry)
+
+	var diff envelopeDiff
+	for _, e := range after.Annotations {
+		b, existed := before[e.ContextName]
+		switch {
+		case !existed:
+			diff.Added = append(diff.Added, e.ContextName)
+		case e.Mode == "delete" && b.Mode != "delete":
+			diff.Deleted = append(diff.Deleted, e.ContextName)
+		case e != b:
+			diff.Updated = append(diff.Updated, e.ContextName)
+		}
+	}
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Updated)
+	sort.Strings(diff.Deleted)
+
+	return after, diff
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// httpExportMaxRetries bounds the number of attempts HTTPExporter makes
+// before giving up on a 5xx or network error.
+const httpExportMaxRetries = 3
+
+// Exporter fans a single AnnotationEntry out to a sink. New sinks (S3,
+// Slack, ...) are added by implementing this interface and wiring a case
+// in parseOutputSpec, without touching the merge logic in mergeEntry.
+type Exporter interface {
+	Export(ctx context.Context, entry AnnotationEntry) error
+}
+
+// stringSliceFlag implements flag.Value to let --output be passed more
+// than once on a single invocation.
+type stringSliceFlag []string
+
+func (s *stringSliceFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringSliceFlag) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// parseOutputSpec parses the `type=...,dest=...` convention used by
+// --output (mirroring buildkit's build outputs flag) into an Exporter.
+func parseOutputSpec(spec string, cli *CLI) (Exporter, error) {
+	fields := map[string]string{}
+	for _, part := range strings.Split(spec, ",") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid --output field %q, expected key=value", part)
+		}
+		fields[kv[0]] = kv[1]
+	}
+
+	switch fields["type"] {
+	case "", "file":
+		dest := fields["dest"]
+		if dest == "" {
+			dest = cli.annotationsFile
+		}
+		return &FileExporter{cli: cli, dest: dest}, nil
+
+	case "jsonl":
+		dest := fields["dest"]
+		if dest == "" {
+			dest = "-"
+		}
+		return &JSONLExporter{dest: dest}, nil
+
+	case "http":
+		url := fields["url"]
+		if url == "" {
+			return nil, fmt.Errorf("--output type=http requires url=...")
+		}
+		headers := http.Header{}
+		if h, ok := fields["header"]; ok {
+			kv := strings.SplitN(h, ":", 2)
+			if len(kv) != 2 {
+				return nil, fmt.Errorf("invalid --output header %q, expected Key:Value", h)
+			}
+			headers.Set(strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1]))
+		}
+		return &HTTPExporter{url: url, headers: headers, client: &http.Client{Timeout: 10 * time.Second}}, nil
+
+	default:
+		return nil, fmt.Errorf("unknown --output type %q", fields["type"])
+	}
+}
+
+// FileExporter persists an entry into the on-disk AnnotationsEnvelope at
+// dest, taking the same advisory lock and merge-by-context-name semantics
+// that annotate() has always used. This is the default exporter when no
+// --output flags are given.
+type FileExporter struct {
+	cli  *CLI
+	dest string
+}
+
+func (e *FileExporter) Export(ctx context.Context, entry AnnotationEntry) error {
+	lock := newFileLock(e.dest)
+	if err := lock.Acquire(e.cli.lockTimeout); err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	env, err := e.cli.loadEnvelope(e.dest)
+	if err != nil {
+		return err
+	}
+
+	// Ensure planExecutionId is present at the root for lite-engine to post annotations
+	if strings.TrimSpace(env.PlanExecutionID) == "" {
+		if pe := e.cli.getPlanExecutionID(); strings.TrimSpace(pe) != "" {
+			env.PlanExecutionID = pe
+		}
+	}
+
+	mergeEntry(&env, entry)
+
+	return e.cli.saveEnvelope(e.dest, env)
+}
+
+// JSONLExporter streams the entry as a single line-delimited JSON object,
+// either to stdout ("-") or appended to a file.
+type JSONLExporter struct {
+	dest string
+}
+
+func (e *JSONLExporter) Export(ctx context.Context, entry AnnotationEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	if e.dest == "" || e.dest == "-" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+
+	f, err := os.OpenFile(e.dest, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open jsonl dest '%s': %w", e.dest, err)
+	}
+	defer f.Close()
+
+	_, err = f.Write(data)
+	return err
+}
+
+// HTTPExporter POSTs the entry as JSON to a webhook URL, retrying 5xx and
+// network errors with exponential backoff.
+type HTTPExporter struct {
+	url     string
+	headers http.Header
+	client  *http.Client
+}
+
+func (e *HTTPExporter) Export(ctx context.Context, entry AnnotationEntry) error {
+	body, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	var lastErr error
+	backoff := 200 * time.Millisecond
+	for attempt := 1; attempt <= httpExportMaxRetries; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+		if err != nil {
+			return fmt.Errorf("failed to build webhook request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		for k, vs := range e.headers {
+			for _, v := range vs {
+				req.Header.Add(k, v)
+			}
+		}
+
+		resp, doErr := e.client.Do(req)
+		if doErr != nil {
+			lastErr = doErr
+		} else {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			if resp.StatusCode < 300 {
+				return nil
+			}
+			lastErr = fmt.Errorf("webhook %s returned status %d", e.url, resp.StatusCode)
+			if resp.StatusCode < 500 {
+				return lastErr
+			}
+		}
+
+		if attempt < httpExportMaxRetries {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+	return fmt.Errorf("webhook %s failed after %d attempts: %w", e.url, httpExportMaxRetries, lastErr)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+// lockTimeoutUnset sentinels the --lock-timeout flag's default so an
+// explicit 0 (wait forever, per FileLock.Acquire below) can be told apart
+// from the flag not having been passed at all.
+const lockTimeoutUnset time.Duration = -1
+
+// addLockTimeoutFlag registers the --lock-timeout flag shared by every
+// subcommand that takes the annotations file lock.
+func addLockTimeoutFlag(fs *flag.FlagSet) *time.Duration {
+	return fs.Duration("lock-timeout", lockTimeoutUnset, "Max time to wait for the annotations file lock, e.g. 10s (0 = wait forever). Optional; defaults to HARNESS_ANNOTATIONS_LOCK_TIMEOUT or 10s")
+}
+
+// applyLockTimeout overrides cli's lock timeout when --lock-timeout was
+// explicitly passed, including an explicit 0 meaning wait forever.
+func applyLockTimeout(cli *CLI, lockTimeout time.Duration) {
+	if lockTimeout != lockTimeoutUnset {
+		cli.lockTimeout = lockTimeout
+	}
+}
+
+// FileLock is an OS-level advisory lock backed by a sibling ".lock" file.
+// It guards the load->mutate->save cycle around the annotations file so
+// that concurrent `annotate` invocations (e.g. from parallel pipeline
+// steps) don't clobber each other's writes.
+type FileLock struct {
+	path string
+	file *os.File
+}
+
+func newFileLock(annotationsFile string) *FileLock {
+	return &FileLock{path: annotationsFile + ".lock"}
+}
+
+// Acquire polls the platform-specific non-blocking lock primitive with
+// backoff until the lock is held or timeout elapses. A timeout <= 0 means
+// wait forever.
+func (l *FileLock) Acquire(timeout time.Duration) error {
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open lock file '%s': %w", l.path, err)
+	}
+
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+
+	backoff := 10 * time.Millisecond
+	for {
+		if err := tryLockFile(f); err == nil {
+			l.file = f
+			return nil
+		}
+		if timeout > 0 && time.Now().After(deadline) {
+			f.Close()
+			return fmt.Errorf("timed out after %s waiting for lock '%s'", timeout, l.path)
+		}
+		time.Sleep(backoff)
+		if backoff < 200*time.Millisecond {
+			backoff *= 2
+		}
+	}
+}
+
+// Release unlocks and closes the underlying lock file. It is a no-op if
+// the lock was never acquired.
+func (l *FileLock) Release() error {
+	if l.file == nil {
+		return nil
+	}
+	unlockErr := unlockFile(l.file)
+	closeErr := l.file.Close()
+	l.file = nil
+	if unlockErr != nil {
+		return unlockErr
+	}
+	return closeErr
+}
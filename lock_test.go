@@ -0,0 +1,61 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileLockAcquireTimesOutWhileHeld(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "annotations.json")
+
+	holder := newFileLock(path)
+	if err := holder.Acquire(time.Second); err != nil {
+		t.Fatalf("holder.Acquire: %v", err)
+	}
+	defer holder.Release()
+
+	waiter := newFileLock(path)
+	start := time.Now()
+	err := waiter.Acquire(50 * time.Millisecond)
+	if err == nil {
+		waiter.Release()
+		t.Fatal("Acquire succeeded while the lock was held by another FileLock")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Acquire took %s to report the timeout, want close to 50ms", elapsed)
+	}
+}
+
+func TestFileLockAcquireZeroWaitsForever(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "annotations.json")
+
+	holder := newFileLock(path)
+	if err := holder.Acquire(time.Second); err != nil {
+		t.Fatalf("holder.Acquire: %v", err)
+	}
+
+	waiter := newFileLock(path)
+	done := make(chan error, 1)
+	go func() { done <- waiter.Acquire(0) }()
+
+	select {
+	case err := <-done:
+		t.Fatalf("Acquire(0) returned (err=%v) before the lock was released; want it to block", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	if err := holder.Release(); err != nil {
+		t.Fatalf("holder.Release: %v", err)
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("waiter.Acquire(0) after release: %v", err)
+		}
+		waiter.Release()
+	case <-time.After(2 * time.Second):
+		t.Fatal("Acquire(0) never returned after the lock was released")
+	}
+}
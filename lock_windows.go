@@ -0,0 +1,49 @@
+//go:build windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	modkernel32      = syscall.NewLazyDLL("kernel32.dll")
+	procLockFileEx   = modkernel32.NewProc("LockFileEx")
+	procUnlockFileEx = modkernel32.NewProc("UnlockFileEx")
+)
+
+const (
+	lockfileFailImmediately = 0x00000001
+	lockfileExclusiveLock   = 0x00000002
+)
+
+func tryLockFile(f *os.File) error {
+	ol := new(syscall.Overlapped)
+	r, _, err := procLockFileEx.Call(
+		f.Fd(),
+		uintptr(lockfileFailImmediately|lockfileExclusiveLock),
+		0,
+		1, 0,
+		uintptr(unsafe.Pointer(ol)),
+	)
+	if r == 0 {
+		return err
+	}
+	return nil
+}
+
+func unlockFile(f *os.File) error {
+	ol := new(syscall.Overlapped)
+	r, _, err := procUnlockFileEx.Call(
+		f.Fd(),
+		0,
+		1, 0,
+		uintptr(unsafe.Pointer(ol)),
+	)
+	if r == 0 {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// newLogger builds the leveled slog.Logger used for CLI diagnostics. It
+// writes JSON when HARNESS_LOG_FORMAT=json (so log aggregators can parse
+// it) and human-readable text otherwise, both to stderr so stdout stays
+// reserved for the tool's JSON result.
+func newLogger(level string) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: parseLogLevel(level)}
+
+	var handler slog.Handler
+	if strings.EqualFold(os.Getenv("HARNESS_LOG_FORMAT"), "json") {
+		handler = slog.NewJSONHandler(os.Stderr, opts)
+	} else {
+		handler = slog.NewTextHandler(os.Stderr, opts)
+	}
+	return slog.New(handler)
+}
+
+// parseLogLevel maps an arbitrary --log-level value onto a slog.Level,
+// defaulting unknown or empty values to info.
+func parseLogLevel(level string) slog.Level {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
@@ -1,7 +1,9 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -13,38 +15,51 @@ import (
 
 const MaxSummaryFileBytes = 64 * 1024 // 64KB limit for a single summary file
 
-// On-disk structure (annotations-only):
+// defaultLockTimeout bounds how long `annotate` waits for the sibling
+// ".lock" file before giving up, so a stuck holder can't hang a pipeline
+// step forever.
+const defaultLockTimeout = 10 * time.Second
+
+// On-disk structure (annotations-only), schema version CurrentSchemaVersion:
 // {
+//   "schemaVersion": 2,
 //   "annotations": [
 //     {
 //       "context_name": "build",
 //       "timestamp": "RFC3339",
 //       "style": "info|success|warning|error",
 //       "summary": "markdown...",
+//       "summary_format": "markdown|plain|html",
 //       "summary_file": "path (echo)",
 //       "priority": 0,
 //       "mode": "append|replace|delete" // optional; defaults to append at engine side if omitted
 //     }
 //   ]
 // }
+// See schema.go for the migrations table that loadEnvelope uses to upgrade
+// older files in memory; the file itself is rewritten at the current
+// version only when saveEnvelope runs.
 
 type AnnotationEntry struct {
-	ContextName string `json:"context_name"`
-	Timestamp   string `json:"timestamp"`
-	Style       string `json:"style"`
-	Summary     string `json:"summary"`
-	SummaryFile string `json:"summary_file"`
-	Priority    int    `json:"priority"`
-	Mode        string `json:"mode,omitempty"`
+	ContextName   string `json:"context_name"`
+	Timestamp     string `json:"timestamp"`
+	Style         string `json:"style"`
+	Summary       string `json:"summary"`
+	SummaryFormat string `json:"summary_format,omitempty"`
+	SummaryFile   string `json:"summary_file"`
+	Priority      int    `json:"priority"`
+	Mode          string `json:"mode,omitempty"`
 }
 
 type AnnotationsEnvelope struct {
+	SchemaVersion   int               `json:"schemaVersion,omitempty"`
 	PlanExecutionID string            `json:"planExecutionId,omitempty"`
 	Annotations     []AnnotationEntry `json:"annotations"`
 }
 
 type CLI struct {
 	annotationsFile string
+	lockTimeout     time.Duration
 }
 
 func NewCLI() *CLI {
@@ -52,19 +67,26 @@ func NewCLI() *CLI {
 	if outputPath == "" {
 		outputPath = "annotations.json"
 	}
+	lockTimeout := defaultLockTimeout
+	if v := os.Getenv("HARNESS_ANNOTATIONS_LOCK_TIMEOUT"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			lockTimeout = d
+		}
+	}
 	return &CLI{
 		annotationsFile: outputPath,
+		lockTimeout:     lockTimeout,
 	}
 }
 
-func (c *CLI) loadEnvelope() (AnnotationsEnvelope, error) {
-	env := AnnotationsEnvelope{}
+func (c *CLI) loadEnvelope(path string) (AnnotationsEnvelope, error) {
+	env := AnnotationsEnvelope{SchemaVersion: CurrentSchemaVersion}
 
-	if _, err := os.Stat(c.annotationsFile); os.IsNotExist(err) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
 		return env, nil
 	}
 
-	data, err := os.ReadFile(c.annotationsFile)
+	data, err := os.ReadFile(path)
 	if err != nil {
 		return env, err
 	}
@@ -73,20 +95,27 @@ func (c *CLI) loadEnvelope() (AnnotationsEnvelope, error) {
 		return env, nil
 	}
 
-	if err := json.Unmarshal(data, &env); err != nil {
+	migrated, err := migrateToCurrentSchema(data)
+	if err != nil {
+		return env, err
+	}
+
+	if err := json.Unmarshal(migrated, &env); err != nil {
 		return env, fmt.Errorf("invalid annotations file format: %w", err)
 	}
 	return env, nil
 }
 
-func (c *CLI) saveEnvelope(env AnnotationsEnvelope) error {
+func (c *CLI) saveEnvelope(path string, env AnnotationsEnvelope) error {
+	env.SchemaVersion = CurrentSchemaVersion
+
 	data, err := json.MarshalIndent(env, "", "  ")
 	if err != nil {
 		return err
 	}
 
 	// Ensure parent directory exists
-	dir := filepath.Dir(c.annotationsFile)
+	dir := filepath.Dir(path)
 	if dir != "" && dir != "." {
 		if err := os.MkdirAll(dir, 0o755); err != nil {
 			return fmt.Errorf("failed to create parent dir: %w", err)
@@ -94,14 +123,14 @@ func (c *CLI) saveEnvelope(env AnnotationsEnvelope) error {
 	}
 
 	// Atomic write pattern: write to tmp and then rename to final
-	tmp := c.annotationsFile + ".tmp"
+	tmp := path + ".tmp"
 	if err := os.WriteFile(tmp, data, 0o644); err != nil {
 		return fmt.Errorf("failed to write temp file: %w", err)
 	}
-	if err := os.Rename(tmp, c.annotationsFile); err != nil {
+	if err := os.Rename(tmp, path); err != nil {
 		// On Windows, rename may fail if destination exists. Try removing and renaming again.
-		_ = os.Remove(c.annotationsFile)
-		if err2 := os.Rename(tmp, c.annotationsFile); err2 != nil {
+		_ = os.Remove(path)
+		if err2 := os.Rename(tmp, path); err2 != nil {
 			_ = os.Remove(tmp)
 			return fmt.Errorf("failed to finalize write: %w", err2)
 		}
@@ -139,129 +168,187 @@ func (c *CLI) readSummaryFile(filePath string) (string, error) {
 	return string(data), nil
 }
 
-func (c *CLI) annotate(contextName, style, summaryFile, mode string, priority int) (map[string]interface{}, error) {
-	env, err := c.loadEnvelope()
-	if err != nil {
-		return nil, err
-	}
-
-	// Ensure planExecutionId is present at the root for lite-engine to post annotations
-	if strings.TrimSpace(env.PlanExecutionID) == "" {
-		if pe := c.getPlanExecutionID(); strings.TrimSpace(pe) != "" {
-			env.PlanExecutionID = pe
-		}
-	}
-
-	summary, err := c.readSummaryFile(summaryFile)
-	if err != nil {
-		return nil, err
-	}
-
-	stepId := c.getStepID()
-
-	// Normalize mode
+// normalizeMode maps an arbitrary --mode value onto a known mode,
+// defaulting unknown or empty values to "replace".
+func normalizeMode(mode string) string {
 	switch mode {
 	case "replace", "append", "delete":
-		// ok
-	case "":
-		mode = "replace"
+		return mode
 	default:
-		// unknown -> default to replace
-		mode = "replace"
+		return "replace"
 	}
+}
 
-	// Find existing entry for this context
+// mergeEntry folds the AnnotationEntry built for this invocation into env,
+// honoring in.Mode against any existing entry for the same context. This is
+// the merge logic shared by every FileExporter destination.
+func mergeEntry(env *AnnotationsEnvelope, in AnnotationEntry) {
 	idx := -1
 	for i := range env.Annotations {
-		if env.Annotations[i].ContextName == contextName {
+		if env.Annotations[i].ContextName == in.ContextName {
 			idx = i
 			break
 		}
 	}
 
 	if idx == -1 {
-		// New context entry
-		env.Annotations = append(env.Annotations, AnnotationEntry{
-			ContextName: contextName,
-			Timestamp:   time.Now().Format(time.RFC3339),
-			Style:       style,
-			Summary:     summary,
-			SummaryFile: summaryFile,
-			Priority:    priority,
-			Mode:        mode,
-		})
-	} else {
-		// Merge into existing entry based on mode
-		entry := env.Annotations[idx]
-		entry.Timestamp = time.Now().Format(time.RFC3339)
-		if mode == "delete" {
-			// mark as delete; content not needed
-			entry.Mode = "delete"
-			entry.Summary = ""
-			entry.Style = ""
-			entry.Priority = 0
-		} else if mode == "replace" {
-			if style != "" {
-				entry.Style = style
-			}
-			entry.Summary = summary
-			entry.Mode = "replace"
-			if priority > 0 {
-				entry.Priority = priority
-			}
-			if summaryFile != "" {
-				entry.SummaryFile = summaryFile
-			}
-		} else { // append
-			if style != "" {
-				entry.Style = style
-			}
-			if summary != "" {
-				if entry.Summary != "" {
-					entry.Summary += "\n" + summary
-				} else {
-					entry.Summary = summary
-				}
-			}
-			entry.Mode = "append"
-			if priority > 0 {
-				entry.Priority = priority
-			}
-			if summaryFile != "" {
-				entry.SummaryFile = summaryFile
+		env.Annotations = append(env.Annotations, in)
+		return
+	}
+
+	existing := env.Annotations[idx]
+	existing.Timestamp = in.Timestamp
+	switch in.Mode {
+	case "delete":
+		// mark as delete; content not needed
+		existing.Mode = "delete"
+		existing.Summary = ""
+		existing.Style = ""
+		existing.Priority = 0
+	case "append":
+		if in.Style != "" {
+			existing.Style = in.Style
+		}
+		if in.Summary != "" {
+			if existing.Summary != "" {
+				existing.Summary += "\n" + in.Summary
+			} else {
+				existing.Summary = in.Summary
 			}
 		}
-		env.Annotations[idx] = entry
+		existing.Mode = "append"
+		if in.Priority > 0 {
+			existing.Priority = in.Priority
+		}
+		if in.SummaryFile != "" {
+			existing.SummaryFile = in.SummaryFile
+		}
+		if in.SummaryFormat != "" {
+			existing.SummaryFormat = in.SummaryFormat
+		}
+	default: // replace
+		if in.Style != "" {
+			existing.Style = in.Style
+		}
+		existing.Summary = in.Summary
+		existing.Mode = "replace"
+		if in.Priority > 0 {
+			existing.Priority = in.Priority
+		}
+		if in.SummaryFile != "" {
+			existing.SummaryFile = in.SummaryFile
+		}
+		if in.SummaryFormat != "" {
+			existing.SummaryFormat = in.SummaryFormat
+		}
 	}
+	env.Annotations[idx] = existing
+}
+
+// resolveExporters turns the repeatable --output specs into Exporters. With
+// no --output flags at all it preserves the historical behavior of writing
+// only to c.annotationsFile.
+func (c *CLI) resolveExporters(specs []string) ([]Exporter, error) {
+	if len(specs) == 0 {
+		return []Exporter{&FileExporter{cli: c, dest: c.annotationsFile}}, nil
+	}
+
+	exporters := make([]Exporter, 0, len(specs))
+	for _, spec := range specs {
+		exp, err := parseOutputSpec(spec, c)
+		if err != nil {
+			return nil, err
+		}
+		exporters = append(exporters, exp)
+	}
+	return exporters, nil
+}
 
-	if err := c.saveEnvelope(env); err != nil {
+// resolveDryRunDest picks the on-disk file --dry-run should preview its
+// merge against: the dest of the first file-type exporter resolveExporters
+// would use for outputs. --dry-run has nothing to preview against a
+// webhook or a stdout/append-only jsonl sink, so it's rejected unless
+// outputs resolves to at least one type=file exporter (or is empty, which
+// resolveExporters already defaults to c.annotationsFile).
+func (c *CLI) resolveDryRunDest(outputs []string) (string, error) {
+	exporters, err := c.resolveExporters(outputs)
+	if err != nil {
+		return "", err
+	}
+	for _, exp := range exporters {
+		if fe, ok := exp.(*FileExporter); ok {
+			return fe.dest, nil
+		}
+	}
+	return "", fmt.Errorf("--dry-run requires at least one type=file --output (or none, to preview the default %s); got: %s", c.annotationsFile, strings.Join(outputs, " "))
+}
+
+// annotate fans entry out to outputs and returns the step result for the
+// CLI's stdout JSON.
+func (c *CLI) annotate(entry AnnotationEntry, outputs []string) (map[string]interface{}, error) {
+	exporters, err := c.resolveExporters(outputs)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	// Exporters run independently: one sink failing (e.g. a down webhook)
+	// must not stop the rest, since an earlier, unrelated sink shouldn't be
+	// able to silently skip the authoritative file write.
+	var errs []error
+	for _, exp := range exporters {
+		if err := exp.Export(ctx, entry); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
 		return nil, err
 	}
 
+	stepId := c.getStepID()
 	result := map[string]interface{}{
-		"context": contextName,
+		"context": entry.ContextName,
 		"stepid":  stepId,
-		"message": fmt.Sprintf("Annotation stored for context '%s' with step ID '%s'", contextName, stepId),
+		"message": fmt.Sprintf("Annotation stored for context '%s' with step ID '%s'", entry.ContextName, stepId),
 	}
 	return result, nil
 }
 
+// commands maps each subcommand name to its handler. Every handler parses
+// its own flag.FlagSet and is responsible for its own exit behavior,
+// mirroring how `annotate` has always worked.
+var commands = map[string]func(args []string){
+	"annotate": runAnnotate,
+	"list":     runList,
+	"get":      runGet,
+	"delete":   runDelete,
+	"clear":    runClear,
+	"prune":    runPrune,
+}
+
+func usage(prog string) {
+	fmt.Printf("Usage: %s <command> [flags]\n", prog)
+	fmt.Println("Available commands: annotate, list, get, delete, clear, prune")
+}
+
 func main() {
 	prog := filepath.Base(os.Args[0])
 	if len(os.Args) < 2 {
-		fmt.Printf("Usage: %s annotate [flags]\n", prog)
+		usage(prog)
 		// Non-fatal for pipelines
 		os.Exit(0)
 	}
 
-	command := os.Args[1]
-
-	if command != "annotate" {
-		fmt.Printf("Usage: %s annotate [flags]\n", prog)
-		fmt.Println("Available commands: annotate")
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		usage(prog)
 		os.Exit(0)
 	}
 
+	cmd(os.Args[2:])
+}
+
+func runAnnotate(args []string) {
 	fs := flag.NewFlagSet("annotate", flag.ContinueOnError)
 	// suppress default usage output on parse errors; we'll control messaging
 	fs.SetOutput(io.Discard)
@@ -270,24 +357,103 @@ func main() {
 	summary := fs.String("summary", "", "Path to summary file (markdown content)")
 	mode := fs.String("mode", "replace", "Annotation mode (append|replace|delete). Optional; defaults to replace")
 	priority := fs.Int("priority", 0, "Annotation priority (int). Optional")
-
-	if err := fs.Parse(os.Args[2:]); err != nil {
-		fmt.Fprintf(os.Stderr, "[ANN_CLI] warning: failed to parse flags: %v\n", err)
+	lockTimeout := addLockTimeoutFlag(fs)
+	var outputs stringSliceFlag
+	fs.Var(&outputs, "output", "Exporter spec (repeatable): type=file,dest=... | type=jsonl,dest=-|PATH | type=http,url=...,header=Key:Value. Optional; defaults to type=file,dest=$HARNESS_ANNOTATIONS_FILE")
+	emit := fs.String("emit", "", "Additional emission mode alongside the stored annotation (workflow-commands)")
+	group := fs.String("group", "", "With --emit=workflow-commands, wrap output in ::group::NAME/::endgroup::")
+	summaryFormat := fs.String("summary-format", "markdown", "Format of --summary content (markdown|plain|html)")
+	schemaOnly := fs.Bool("schema-only", false, "Print the current annotations file JSON schema and exit")
+	summaryTail := fs.String("summary-tail", "", "Tail the last N bytes of PATH[:N] instead of reading it whole (default N=64KB, capped there)")
+	summaryStdin := fs.Bool("summary-stdin", false, "Read the summary from stdin instead of --summary, capped at 64KB")
+	logLevel := fs.String("log-level", "info", "Log level for diagnostics (debug|info|warn|error)")
+	dryRun := fs.Bool("dry-run", false, "Run the merge logic and print the resulting diff without touching disk")
+	verbose := fs.Bool("verbose", false, "Log the resolved file path, mode, before/after entry, and elapsed time")
+
+	logger := newLogger("info")
+	if err := fs.Parse(args); err != nil {
+		logger.Warn("failed to parse flags", "error", err)
 		os.Exit(0)
 	}
+	logger = newLogger(*logLevel)
+
+	if *schemaOnly {
+		printSchema()
+		return
+	}
 
 	if *context == "" {
-		fmt.Fprintln(os.Stderr, "[ANN_CLI] warning: --context is required")
+		logger.Warn("--context is required")
 		os.Exit(0)
 	}
 
+	start := time.Now()
 	cli := NewCLI()
-	result, err := cli.annotate(*context, *style, *summary, *mode, *priority)
+	applyLockTimeout(cli, *lockTimeout)
+
+	if *verbose {
+		logger.Info("resolved annotation target", "file", cli.annotationsFile, "mode", normalizeMode(*mode), "lockTimeout", cli.lockTimeout)
+	}
+
+	summaryContent, summaryFileEcho, err := resolveSummarySource(cli, *summary, *summaryTail, *summaryStdin)
+	if err != nil {
+		logger.Warn(err.Error())
+		os.Exit(0)
+	}
+
+	entry := AnnotationEntry{
+		ContextName:   *context,
+		Timestamp:     time.Now().Format(time.RFC3339),
+		Style:         *style,
+		Summary:       summaryContent,
+		SummaryFormat: normalizeSummaryFormat(*summaryFormat),
+		SummaryFile:   summaryFileEcho,
+		Priority:      *priority,
+		Mode:          normalizeMode(*mode),
+	}
+
+	if *dryRun {
+		dryRunDest, err := cli.resolveDryRunDest(outputs)
+		if err != nil {
+			logger.Warn(err.Error())
+			os.Exit(0)
+		}
+		before, err := cli.loadEnvelope(dryRunDest)
+		if err != nil {
+			logger.Warn(err.Error())
+			os.Exit(0)
+		}
+		after, diff := diffEnvelope(before, entry)
+		if *verbose {
+			logger.Info("dry run complete", "before", before, "after", after, "elapsed", time.Since(start))
+		}
+		diffJSON, _ := json.MarshalIndent(diff, "", "  ")
+		fmt.Println(string(diffJSON))
+		return
+	}
+
+	var beforeEntry AnnotationEntry
+	if *verbose {
+		beforeEntry, _, _ = cli.Get(*context)
+	}
+
+	result, err := cli.annotate(entry, outputs)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "[ANN_CLI] warning: %v\n", err)
+		logger.Warn(err.Error())
 		os.Exit(0)
 	}
 
+	if *emit == "workflow-commands" {
+		if err := emitWorkflowCommands(os.Stdout, entry, *group); err != nil {
+			logger.Warn("failed to emit workflow commands", "error", err)
+		}
+	}
+
+	if *verbose {
+		afterEntry, _, _ := cli.Get(*context)
+		logger.Info("annotation stored", "entry", entry, "before", beforeEntry, "after", afterEntry, "elapsed", time.Since(start))
+	}
+
 	resultJSON, _ := json.MarshalIndent(result, "", "  ")
 	fmt.Println(string(resultJSON))
 }
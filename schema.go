@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// CurrentSchemaVersion is the schemaVersion this binary writes. Bump it
+// and add a migrations[[2]int{CurrentSchemaVersion-1, CurrentSchemaVersion}]
+// entry whenever the on-disk AnnotationsEnvelope/AnnotationEntry layout
+// changes, so older and newer CLIs reading the same file don't desync.
+const CurrentSchemaVersion = 2
+
+// migrationFunc transforms a raw envelope document from one schema version
+// to the next.
+type migrationFunc func(raw json.RawMessage) (json.RawMessage, error)
+
+// migrations maps (from, to) schema version pairs to the function that
+// upgrades a raw document between them. migrateToCurrentSchema walks this
+// table one step at a time until it reaches CurrentSchemaVersion.
+var migrations = map[[2]int]migrationFunc{
+	{1, 2}: migrateV1ToV2,
+}
+
+// migrateV1ToV2 adds summary_format ("markdown", matching this tool's
+// original assumption) to every annotation that predates the field, and
+// stamps the envelope with schemaVersion 2.
+func migrateV1ToV2(raw json.RawMessage) (json.RawMessage, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("v1->v2 migration: %w", err)
+	}
+
+	if anns, ok := doc["annotations"].([]interface{}); ok {
+		for _, a := range anns {
+			entry, ok := a.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if _, has := entry["summary_format"]; !has {
+				entry["summary_format"] = "markdown"
+			}
+		}
+	}
+	doc["schemaVersion"] = 2
+
+	return json.Marshal(doc)
+}
+
+// detectSchemaVersion reads the schemaVersion field, treating its absence
+// (every file written before this field existed) as schema v1.
+func detectSchemaVersion(raw json.RawMessage) (int, error) {
+	var probe struct {
+		SchemaVersion int `json:"schemaVersion"`
+	}
+	if err := json.Unmarshal(raw, &probe); err != nil {
+		return 0, fmt.Errorf("invalid annotations file format: %w", err)
+	}
+	if probe.SchemaVersion == 0 {
+		return 1, nil
+	}
+	return probe.SchemaVersion, nil
+}
+
+// migrateToCurrentSchema runs raw through the migrations table, one schema
+// version at a time, until it reaches CurrentSchemaVersion.
+func migrateToCurrentSchema(raw json.RawMessage) (json.RawMessage, error) {
+	version, err := detectSchemaVersion(raw)
+	if err != nil {
+		return nil, err
+	}
+	if version > CurrentSchemaVersion {
+		return nil, fmt.Errorf("annotations file schema v%d is newer than the supported v%d", version, CurrentSchemaVersion)
+	}
+
+	for version < CurrentSchemaVersion {
+		migrate, ok := migrations[[2]int{version, version + 1}]
+		if !ok {
+			return nil, fmt.Errorf("no migration registered from schema v%d to v%d", version, version+1)
+		}
+		raw, err = migrate(raw)
+		if err != nil {
+			return nil, fmt.Errorf("migrating schema v%d->v%d: %w", version, version+1, err)
+		}
+		version++
+	}
+	return raw, nil
+}
+
+// normalizeSummaryFormat maps an arbitrary --summary-format value onto a
+// known format, defaulting unknown or empty values to "markdown".
+func normalizeSummaryFormat(format string) string {
+	switch format {
+	case "markdown", "plain", "html":
+		return format
+	default:
+		return "markdown"
+	}
+}
+
+// annotationsSchema is the JSON Schema for the current on-disk envelope,
+// printed by --schema-only. schemaVersion's const is filled in from
+// CurrentSchemaVersion so a version bump can't update one and forget the
+// other.
+var annotationsSchema = fmt.Sprintf(`{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "title": "AnnotationsEnvelope",
+  "type": "object",
+  "properties": {
+    "schemaVersion": { "type": "integer", "const": %d },
+    "planExecutionId": { "type": "string" },
+    "annotations": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "properties": {
+          "context_name": { "type": "string" },
+          "timestamp": { "type": "string", "format": "date-time" },
+          "style": { "type": "string", "enum": ["", "info", "success", "warning", "error"] },
+          "summary": { "type": "string" },
+          "summary_format": { "type": "string", "enum": ["markdown", "plain", "html"] },
+          "summary_file": { "type": "string" },
+          "priority": { "type": "integer" },
+          "mode": { "type": "string", "enum": ["append", "replace", "delete"] }
+        },
+        "required": ["context_name", "timestamp"]
+      }
+    }
+  },
+  "required": ["annotations"]
+}
+`, CurrentSchemaVersion)
+
+func printSchema() {
+	fmt.Print(annotationsSchema)
+}
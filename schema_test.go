@@ -0,0 +1,46 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMigrateToCurrentSchemaFromV1(t *testing.T) {
+	raw := json.RawMessage(`{"annotations":[{"context_name":"build"}]}`)
+
+	migrated, err := migrateToCurrentSchema(raw)
+	if err != nil {
+		t.Fatalf("migrateToCurrentSchema: %v", err)
+	}
+
+	var env AnnotationsEnvelope
+	if err := json.Unmarshal(migrated, &env); err != nil {
+		t.Fatalf("unmarshal migrated doc: %v", err)
+	}
+	if env.SchemaVersion != CurrentSchemaVersion {
+		t.Fatalf("schemaVersion = %d, want %d", env.SchemaVersion, CurrentSchemaVersion)
+	}
+	if len(env.Annotations) != 1 || env.Annotations[0].SummaryFormat != "markdown" {
+		t.Fatalf("expected the v1 entry to be backfilled with summary_format=markdown, got %+v", env.Annotations)
+	}
+}
+
+func TestMigrateToCurrentSchemaAlreadyCurrent(t *testing.T) {
+	raw := json.RawMessage(`{"schemaVersion":2,"annotations":[]}`)
+
+	migrated, err := migrateToCurrentSchema(raw)
+	if err != nil {
+		t.Fatalf("migrateToCurrentSchema: %v", err)
+	}
+	if string(migrated) != string(raw) {
+		t.Fatalf("expected an already-current doc to pass through unchanged, got %s", migrated)
+	}
+}
+
+func TestMigrateToCurrentSchemaUnknownVersion(t *testing.T) {
+	raw := json.RawMessage(`{"schemaVersion":99,"annotations":[]}`)
+
+	if _, err := migrateToCurrentSchema(raw); err == nil {
+		t.Fatal("expected an error for a schema version with no migration path")
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// resolveSummarySource picks the summary content for this invocation from
+// whichever of --summary, --summary-tail, or --summary-stdin was given,
+// preferring stdin, then tail, then the plain file read. It also returns
+// the value to echo back onto the stored entry's summary_file.
+func resolveSummarySource(c *CLI, summaryFile, tailSpec string, stdin bool) (content, summaryFileEcho string, err error) {
+	switch {
+	case stdin:
+		content, err = readSummaryStdin(os.Stdin)
+		return content, "-", err
+	case tailSpec != "":
+		path, n, perr := parseSummaryTailSpec(tailSpec)
+		if perr != nil {
+			return "", "", perr
+		}
+		content, err = readSummaryTail(path, n)
+		return content, tailSpec, err
+	default:
+		content, err = c.readSummaryFile(summaryFile)
+		return content, summaryFile, err
+	}
+}
+
+// parseSummaryTailSpec splits a `--summary-tail=PATH[:N]` value into its
+// path and byte count, defaulting N to MaxSummaryFileBytes and capping it
+// there.
+func parseSummaryTailSpec(spec string) (path string, n int64, err error) {
+	path = spec
+	n = MaxSummaryFileBytes
+
+	if idx := strings.LastIndex(spec, ":"); idx != -1 {
+		if count, perr := strconv.ParseInt(spec[idx+1:], 10, 64); perr == nil {
+			path = spec[:idx]
+			n = count
+		}
+	}
+
+	if path == "" {
+		return "", 0, fmt.Errorf("invalid --summary-tail %q: missing path", spec)
+	}
+	if n <= 0 || n > MaxSummaryFileBytes {
+		n = MaxSummaryFileBytes
+	}
+	return path, n, nil
+}
+
+// readSummaryTail reads the last n bytes of path using a fixed-size window
+// over the file: stat for size, seek to max(0, size-n), then read forward
+// to a rune boundary so a multi-byte UTF-8 sequence isn't split. A
+// truncation marker is prepended when the file is larger than n.
+func readSummaryTail(path string, n int64) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open summary file '%s': %w", path, err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return "", fmt.Errorf("failed to stat summary file '%s': %w", path, err)
+	}
+
+	size := info.Size()
+	start := int64(0)
+	truncated := size > n
+	if truncated {
+		start = size - n
+	}
+
+	if _, err := f.Seek(start, io.SeekStart); err != nil {
+		return "", fmt.Errorf("failed to seek summary file '%s': %w", path, err)
+	}
+
+	buf := make([]byte, size-start)
+	if _, err := io.ReadFull(f, buf); err != nil {
+		return "", fmt.Errorf("failed to read summary file '%s': %w", path, err)
+	}
+
+	if truncated {
+		// Advance past a possibly-split leading rune so decoding doesn't
+		// start mid-character.
+		for len(buf) > 0 && !utf8.RuneStart(buf[0]) {
+			buf = buf[1:]
+		}
+		return fmt.Sprintf("…truncated %d bytes…\n%s", start, string(buf)), nil
+	}
+	return string(buf), nil
+}
+
+// readSummaryStdin reads a summary from r, capped at MaxSummaryFileBytes so
+// a runaway producer can't blow past the same limit enforced on summary
+// files.
+func readSummaryStdin(r io.Reader) (string, error) {
+	data, err := io.ReadAll(io.LimitReader(r, MaxSummaryFileBytes+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to read summary from stdin: %w", err)
+	}
+	if int64(len(data)) > MaxSummaryFileBytes {
+		return "", fmt.Errorf("summary from stdin exceeds %d bytes (64KB)", MaxSummaryFileBytes)
+	}
+	return string(data), nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func writeSummaryFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "summary.md")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	return path
+}
+
+func TestReadSummaryTailSmallerThanWindow(t *testing.T) {
+	path := writeSummaryFile(t, "hello")
+
+	got, err := readSummaryTail(path, 64)
+	if err != nil {
+		t.Fatalf("readSummaryTail: %v", err)
+	}
+	if got != "hello" {
+		t.Fatalf("got %q, want %q (no truncation marker expected)", got, "hello")
+	}
+}
+
+func TestReadSummaryTailTruncatesLargerFile(t *testing.T) {
+	path := writeSummaryFile(t, strings.Repeat("a", 100)+"TAIL")
+
+	got, err := readSummaryTail(path, 4)
+	if err != nil {
+		t.Fatalf("readSummaryTail: %v", err)
+	}
+	if !strings.Contains(got, "truncated") || !strings.HasSuffix(got, "TAIL") {
+		t.Fatalf("got %q, want a truncation marker followed by the last 4 bytes", got)
+	}
+}
+
+func TestReadSummaryTailDoesNotSplitARune(t *testing.T) {
+	// "é" is 2 bytes (0xC3 0xA9); a byte-exact window of 1 would land on
+	// its continuation byte, so the window must be advanced past it
+	// rather than emitting a split/invalid rune.
+	path := writeSummaryFile(t, strings.Repeat("a", 10)+"é")
+
+	got, err := readSummaryTail(path, 1)
+	if err != nil {
+		t.Fatalf("readSummaryTail: %v", err)
+	}
+	if !utf8.ValidString(got) {
+		t.Fatalf("readSummaryTail produced invalid UTF-8: %q", got)
+	}
+}
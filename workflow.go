@@ -0,0 +1,113 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// styleToWorkflowCommand maps an annotation style onto the GitHub Actions
+// workflow command that surfaces it in the Actions UI. Unknown styles fall
+// back to ::notice::.
+var styleToWorkflowCommand = map[string]string{
+	"error":   "error",
+	"warning": "warning",
+	"notice":  "notice",
+}
+
+var envVarSanitizer = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// emitWorkflowCommands prints GitHub-Actions-style workflow commands for
+// entry to w, so the same binary drives annotations on runners that don't
+// understand the Harness envelope. group, if non-empty, wraps the output
+// in ::group::/::endgroup::. Multi-line summaries are also exported via the
+// documented `NAME<<DELIM` heredoc convention into $GITHUB_ENV (when set),
+// since a single ::style:: line can only carry one escaped line; and, when
+// $GITHUB_STEP_SUMMARY is set, the raw markdown is appended there too.
+func emitWorkflowCommands(w io.Writer, entry AnnotationEntry, group string) error {
+	if group != "" {
+		fmt.Fprintf(w, "::group::%s\n", group)
+	}
+
+	cmd, ok := styleToWorkflowCommand[entry.Style]
+	if !ok {
+		cmd = "notice"
+	}
+	fmt.Fprintf(w, "::%s::%s\n", cmd, escapeWorkflowMessage(entry.Summary))
+
+	if group != "" {
+		fmt.Fprintln(w, "::endgroup::")
+	}
+
+	if strings.Contains(entry.Summary, "\n") {
+		if envFile := os.Getenv("GITHUB_ENV"); envFile != "" {
+			name := "ANNOTATE_SUMMARY_" + envVarSanitizer.ReplaceAllString(strings.ToUpper(entry.ContextName), "_")
+			if err := appendHeredocEnv(envFile, name, entry.Summary); err != nil {
+				return err
+			}
+		}
+	}
+
+	if summaryFile := os.Getenv("GITHUB_STEP_SUMMARY"); summaryFile != "" {
+		if err := appendStepSummary(summaryFile, entry.Summary); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// escapeWorkflowMessage applies the escaping GitHub Actions documents for
+// workflow command values (%, CR, LF) so a multi-line summary still
+// renders as a single ::style:: annotation.
+func escapeWorkflowMessage(s string) string {
+	s = strings.ReplaceAll(s, "%", "%25")
+	s = strings.ReplaceAll(s, "\r", "%0D")
+	s = strings.ReplaceAll(s, "\n", "%0A")
+	return s
+}
+
+// appendHeredocEnv appends name's value to a GitHub Actions environment
+// file using the documented `NAME<<DELIM` heredoc format, with a random
+// delimiter so it can't collide with the value itself.
+func appendHeredocEnv(path, name, value string) error {
+	delim, err := randomDelimiter()
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	_, err = fmt.Fprintf(f, "%s<<%s\n%s\n%s\n", name, delim, value, delim)
+	return err
+}
+
+// appendStepSummary appends markdown to the GitHub Actions job summary
+// file, which renders whatever is written to it verbatim.
+func appendStepSummary(path, markdown string) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	_, err = fmt.Fprintf(f, "%s\n", markdown)
+	return err
+}
+
+// randomDelimiter returns a fixed-length random hex token suitable as a
+// heredoc delimiter.
+func randomDelimiter() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate delimiter: %w", err)
+	}
+	return "ghadelim_" + hex.EncodeToString(buf), nil
+}